@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mitchellh/hashstructure"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+// Client is the minimal persistence surface Controller needs to write a
+// reconciled KerberosPrincipalSet back to the API server. It is satisfied by
+// the KerberosPrincipalSets(namespace) interface of a generated clientset,
+// or by a test double.
+type Client interface {
+	Update(set *KerberosPrincipalSet) (*KerberosPrincipalSet, error)
+}
+
+// Controller watches KerberosPrincipalSet resources and reconciles them
+// against KDC and the DC/OS secret store through the same service.Service
+// that backs KDCAPIServer and KDCGRPCServer. This turns the imperative
+// /api/principals endpoint into a GitOps-friendly flow: operators edit the
+// CRD, the controller makes it so.
+type Controller struct {
+	svc      *service.Service
+	client   Client
+	informer cache.SharedIndexInformer
+	queue    workqueue.RateLimitingInterface
+
+	// selector restricts this controller to a subset of KerberosPrincipalSet
+	// resources, so that multiple KDC instances can shard the workload by
+	// label instead of racing each other over the same set.
+	selector labels.Selector
+}
+
+// NewController wires a Service, a Client to persist reconciled status, and
+// an informer for KerberosPrincipalSet into a Controller. selector may be
+// labels.Everything() to watch all resources in the informer's scope.
+func NewController(svc *service.Service, client Client, informer cache.SharedIndexInformer, selector labels.Selector) *Controller {
+	c := &Controller{
+		svc:      svc,
+		client:   client,
+		informer: informer,
+		selector: selector,
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(old, new interface{}) { c.enqueue(new) },
+		DeleteFunc: c.enqueue,
+	})
+
+	return c
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Run starts the informer and workers, blocking until stopCh is closed.
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) error {
+	defer c.queue.ShutDown()
+
+	go c.informer.Run(stopCh)
+	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
+		return fmt.Errorf("timed out waiting for KerberosPrincipalSet cache to sync")
+	}
+
+	for i := 0; i < workers; i++ {
+		go c.runWorker()
+	}
+
+	<-stopCh
+	return nil
+}
+
+func (c *Controller) runWorker() {
+	for c.processNextItem() {
+	}
+}
+
+func (c *Controller) processNextItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.reconcile(key.(string))
+	if err == nil {
+		c.queue.Forget(key)
+		return true
+	}
+
+	// Back off on KDC errors instead of hot-looping the same namespace/name.
+	if c.queue.NumRequeues(key) < 5 {
+		c.queue.AddRateLimited(key)
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+// reconcile is keyed by "<namespace>/<name>", matching the informer's own
+// key function, so work items from different resources never collide.
+func (c *Controller) reconcile(key string) error {
+	obj, exists, err := c.informer.GetIndexer().GetByKey(key)
+	if err != nil {
+		return fmt.Errorf("Unable to fetch KerberosPrincipalSet %s: %s", key, err.Error())
+	}
+	if !exists {
+		// The resource was deleted - nothing further to reconcile here since
+		// the owning namespace/name no longer exists to hold a status.
+		return nil
+	}
+
+	cached, ok := obj.(*KerberosPrincipalSet)
+	if !ok {
+		return fmt.Errorf("unexpected object type for key %s", key)
+	}
+
+	// Never mutate the object handed back by the indexer in place - it is
+	// the informer's own cache entry, shared with every other reader, and
+	// writing through it races the informer's cache maintenance goroutine.
+	set := cached.DeepCopy()
+
+	if !c.selector.Matches(labels.Set(set.Labels)) {
+		return nil
+	}
+
+	// Deletion must be handled before the checksum shortcut below: marking a
+	// resource for deletion never touches its Spec/Generation, so an already
+	// fully-reconciled resource would otherwise hit the shortcut and return
+	// without ever calling DeletePrincipals, leaking the principal/secret.
+	if set.DeletionTimestamp != nil {
+		_, err := c.svc.DeletePrincipals(service.DeletePrincipalsRequest{
+			Principals: set.Spec.Principals,
+			Secret:     set.Spec.SecretName,
+			Binary:     set.Spec.Binary,
+		})
+		return err
+	}
+
+	checksum, err := specChecksum(set.Spec)
+	if err != nil {
+		return fmt.Errorf("Unable to hash KerberosPrincipalSet spec: %s", err.Error())
+	}
+
+	if set.Annotations[checksumAnnotation] == checksum && set.Status.ObservedGeneration == set.Generation {
+		// Spec hasn't changed since the last successful reconcile.
+		return nil
+	}
+
+	_, err = c.svc.AddPrincipals(service.AddPrincipalsRequest{
+		Principals: set.Spec.Principals,
+		Secret:     set.Spec.SecretName,
+		Binary:     set.Spec.Binary,
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := c.svc.CheckPrincipals(service.CheckPrincipalsRequest{
+		Principals: set.Spec.Principals,
+		Secret:     set.Spec.SecretName,
+		Binary:     set.Spec.Binary,
+	})
+	if err != nil {
+		return err
+	}
+
+	if set.Annotations == nil {
+		set.Annotations = make(map[string]string)
+	}
+	set.Annotations[checksumAnnotation] = checksum
+	set.Status.Checksum = result.Checksum
+	set.Status.ObservedGeneration = set.Generation
+
+	if _, err := c.client.Update(set); err != nil {
+		return fmt.Errorf("Unable to persist reconciled status for %s: %s", key, err.Error())
+	}
+
+	return nil
+}
+
+// specChecksum computes a stable hash over the desired spec so reconcile
+// can tell whether anything actually changed since the last run. The
+// principal list is sorted first so re-ordering the same set in the CRD
+// doesn't trigger a spurious reconcile.
+func specChecksum(spec KerberosPrincipalSetSpec) (string, error) {
+	sorted := make([]service.KPrincipal, len(spec.Principals))
+	copy(sorted, spec.Principals)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Full() < sorted[j].Full() })
+
+	hash, err := hashstructure.Hash(struct {
+		Principals []service.KPrincipal
+		SecretName string
+		Binary     bool
+		Realm      string
+	}{sorted, spec.SecretName, spec.Binary, spec.Realm}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash), nil
+}
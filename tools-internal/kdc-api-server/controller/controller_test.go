@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+type fakeKAdmin struct {
+	deleted [][]service.KPrincipal
+}
+
+func (f *fakeKAdmin) AddMissingPrincipals(principals []service.KPrincipal) error { return nil }
+func (f *fakeKAdmin) GetKeytabForPrincipals(principals []service.KPrincipal) ([]byte, error) {
+	return []byte("keytab"), nil
+}
+func (f *fakeKAdmin) DeletePrincipals(principals []service.KPrincipal) error {
+	f.deleted = append(f.deleted, principals)
+	return nil
+}
+func (f *fakeKAdmin) ListPrincipals(filter string) ([]service.KPrincipal, error) { return nil, nil }
+func (f *fakeKAdmin) HasPrincipal(principal service.KPrincipal) (bool, error)    { return true, nil }
+func (f *fakeKAdmin) HasPrincipalInKeytab(keytabBytes []byte, principal *service.KPrincipal) (bool, error) {
+	return true, nil
+}
+
+type fakeDCOSClient struct{}
+
+func (fakeDCOSClient) CreateKeytabSecret(name string, keytab []byte, binary bool) error { return nil }
+func (fakeDCOSClient) GetKeytabSecret(name string, binary bool) ([]byte, error) {
+	return []byte("keytab"), nil
+}
+func (fakeDCOSClient) DeleteKeytabSecret(name string, binary bool) error { return nil }
+
+type fakeClient struct {
+	updated []*KerberosPrincipalSet
+}
+
+func (f *fakeClient) Update(set *KerberosPrincipalSet) (*KerberosPrincipalSet, error) {
+	f.updated = append(f.updated, set)
+	return set, nil
+}
+
+func newTestInformer() cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc:  func(options metav1.ListOptions) (runtime.Object, error) { return &KerberosPrincipalSetList{}, nil },
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) { return watch.NewFake(), nil },
+		},
+		&KerberosPrincipalSet{},
+		0,
+		cache.Indexers{},
+	)
+}
+
+func TestReconcileDeletionSkipsChecksumShortcut(t *testing.T) {
+	kadmin := &fakeKAdmin{}
+	svc := service.NewService(kadmin, func() (service.DCOSClient, error) { return fakeDCOSClient{}, nil })
+	client := &fakeClient{}
+	informer := newTestInformer()
+
+	set := &KerberosPrincipalSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         "default",
+			Name:              "kdc",
+			Generation:        3,
+			DeletionTimestamp: &metav1.Time{},
+			Annotations: map[string]string{
+				checksumAnnotation: "stale-or-not-the-point",
+			},
+		},
+		Spec: KerberosPrincipalSetSpec{
+			Principals: []service.KPrincipal{{Realm: "EXAMPLE.COM", Primary: "alice"}},
+			SecretName: "kdc/secret",
+		},
+		Status: KerberosPrincipalSetStatus{
+			ObservedGeneration: 3,
+		},
+	}
+	// Give it the checksum the current spec would hash to, exactly mimicking
+	// a resource that was already fully reconciled before being deleted.
+	checksum, err := specChecksum(set.Spec)
+	if err != nil {
+		t.Fatalf("specChecksum: %s", err)
+	}
+	set.Annotations[checksumAnnotation] = checksum
+
+	if err := informer.GetIndexer().Add(set); err != nil {
+		t.Fatalf("seeding indexer: %s", err)
+	}
+
+	c := &Controller{
+		svc:      svc,
+		client:   client,
+		informer: informer,
+		selector: labels.Everything(),
+	}
+
+	if err := c.reconcile("default/kdc"); err != nil {
+		t.Fatalf("reconcile returned an error: %s", err)
+	}
+
+	if len(kadmin.deleted) != 1 {
+		t.Fatalf("expected DeletePrincipals to be called once for a pending-deletion resource that already matched its checksum, got %d calls", len(kadmin.deleted))
+	}
+}
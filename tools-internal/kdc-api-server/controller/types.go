@@ -0,0 +1,57 @@
+package controller
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+// checksumAnnotation records the hash of the spec that was last successfully
+// reconciled, so that AddMissingPrincipals/CreateKeytabSecret only run again
+// when the desired state actually changed. DeletePrincipals is not gated by
+// it: a resource marked for deletion is always cleaned up.
+const checksumAnnotation = "kdc.dcos.io/spec-checksum"
+
+// KerberosPrincipalSet is the CRD a cluster operator edits to declare the
+// set of principals that should exist in KDC and be published to a DC/OS
+// secret, instead of calling POST /api/principals by hand.
+type KerberosPrincipalSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   KerberosPrincipalSetSpec   `json:"spec"`
+	Status KerberosPrincipalSetStatus `json:"status,omitempty"`
+}
+
+type KerberosPrincipalSetSpec struct {
+	// Principals is the desired set of kerberos principals for this secret.
+	Principals []service.KPrincipal `json:"principals"`
+	// SecretName is the DC/OS secret path the resulting keytab is published to.
+	SecretName string `json:"secretName"`
+	// Binary selects whether the secret should be stored as raw bytes.
+	Binary bool `json:"binary,omitempty"`
+	// Realm is the kerberos realm of this set. It is part of the spec
+	// checksum only; principals are created in the realm each entry of
+	// Principals names, and Realm is not applied to them.
+	Realm string `json:"realm"`
+}
+
+type KerberosPrincipalSetStatus struct {
+	// Checksum is the result of getPrincipalsChecksum for the keytab that
+	// was last published for this resource.
+	Checksum string `json:"checksum,omitempty"`
+	// ObservedGeneration is the .metadata.generation the status was computed
+	// from, so stale status can be told apart from a fresh reconcile.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions carries the usual Ready/Degraded style condition list.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// KerberosPrincipalSetList is the standard list wrapper client-go informers
+// expect alongside a CRD type.
+type KerberosPrincipalSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []KerberosPrincipalSet `json:"items"`
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+// DeepCopyInto copies the receiver into out, which must be non-nil.
+func (in *KerberosPrincipalSetSpec) DeepCopyInto(out *KerberosPrincipalSetSpec) {
+	*out = *in
+	if in.Principals != nil {
+		out.Principals = make([]service.KPrincipal, len(in.Principals))
+		copy(out.Principals, in.Principals)
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver.
+func (in *KerberosPrincipalSetSpec) DeepCopy() *KerberosPrincipalSetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosPrincipalSetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out, which must be non-nil.
+func (in *KerberosPrincipalSetStatus) DeepCopyInto(out *KerberosPrincipalSetStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver.
+func (in *KerberosPrincipalSetStatus) DeepCopy() *KerberosPrincipalSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosPrincipalSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto copies the receiver into out, which must be non-nil.
+func (in *KerberosPrincipalSet) DeepCopyInto(out *KerberosPrincipalSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a deep copy of the receiver.
+func (in *KerberosPrincipalSet) DeepCopy() *KerberosPrincipalSet {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosPrincipalSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object, which the informer/cache machinery
+// requires of anything it stores.
+func (in *KerberosPrincipalSet) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto copies the receiver into out, which must be non-nil.
+func (in *KerberosPrincipalSetList) DeepCopyInto(out *KerberosPrincipalSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]KerberosPrincipalSet, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a deep copy of the receiver.
+func (in *KerberosPrincipalSetList) DeepCopy() *KerberosPrincipalSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosPrincipalSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (in *KerberosPrincipalSetList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+const testKeyID = "test-key"
+
+func newTestAuthenticator(t *testing.T) (*jwksAuthenticator, jwk.Key) {
+	t.Helper()
+
+	raw := []byte("super-secret-test-signing-key-0123456789")
+	key, err := jwk.New(raw)
+	if err != nil {
+		t.Fatalf("building test jwk: %s", err)
+	}
+	if err := key.Set(jwk.KeyIDKey, testKeyID); err != nil {
+		t.Fatalf("setting kid: %s", err)
+	}
+	if err := key.Set(jwk.AlgorithmKey, jwa.HS256); err != nil {
+		t.Fatalf("setting alg: %s", err)
+	}
+
+	set := jwk.NewSet()
+	set.Add(key)
+
+	return &jwksAuthenticator{
+		cfg: AuthConfig{JWKSFile: "unused-but-marks-auth-enabled"},
+		set: set,
+	}, key
+}
+
+func signTestToken(t *testing.T, key jwk.Key, scope string, expiry time.Time) string {
+	t.Helper()
+
+	tok := jwt.New()
+	if err := tok.Set(jwt.SubjectKey, "alice"); err != nil {
+		t.Fatalf("setting sub: %s", err)
+	}
+	if scope != "" {
+		if err := tok.Set("scope", scope); err != nil {
+			t.Fatalf("setting scope: %s", err)
+		}
+	}
+	if err := tok.Set(jwt.ExpirationKey, expiry); err != nil {
+		t.Fatalf("setting exp: %s", err)
+	}
+
+	signed, err := jwt.Sign(tok, jwa.HS256, key)
+	if err != nil {
+		t.Fatalf("signing token: %s", err)
+	}
+	return string(signed)
+}
+
+func TestRequireScopeDisabledIsNoop(t *testing.T) {
+	a := &jwksAuthenticator{cfg: AuthConfig{}}
+
+	called := false
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if !called {
+		t.Fatalf("expected the wrapped handler to run when auth is disabled")
+	}
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rw.Code)
+	}
+}
+
+func TestRequireScopeMissingToken(t *testing.T) {
+	a, _ := newTestAuthenticator(t)
+
+	called := false
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		called = true
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if called {
+		t.Fatalf("handler should not run without a bearer token")
+	}
+	if rw.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rw.Code)
+	}
+}
+
+func TestRequireScopeInvalidToken(t *testing.T) {
+	a, _ := newTestAuthenticator(t)
+
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		t.Fatalf("handler should not run for a garbage token")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	req.Header.Set("Authorization", "Bearer not-a-real-jwt")
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if rw.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rw.Code)
+	}
+}
+
+func TestRequireScopeExpiredToken(t *testing.T) {
+	a, key := newTestAuthenticator(t)
+
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		t.Fatalf("handler should not run for an expired token")
+	})
+
+	token := signTestToken(t, key, "kdc:principals:write", time.Now().Add(-time.Hour))
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if rw.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for an expired token, got %d", rw.Code)
+	}
+}
+
+func TestRequireScopeMissingScope(t *testing.T) {
+	a, key := newTestAuthenticator(t)
+
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		t.Fatalf("handler should not run without the required scope")
+	})
+
+	token := signTestToken(t, key, "kdc:principals:read", time.Now().Add(time.Hour))
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if rw.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a token missing the scope, got %d", rw.Code)
+	}
+}
+
+func TestRequireScopePresent(t *testing.T) {
+	a, key := newTestAuthenticator(t)
+
+	var gotClaims Claims
+	handler := a.requireScope("kdc:principals:write", func(rw http.ResponseWriter, req *http.Request) {
+		claims, ok := claimsFromContext(req.Context())
+		if !ok {
+			t.Fatalf("expected Claims to be attached to the request context")
+		}
+		gotClaims = claims
+	})
+
+	token := signTestToken(t, key, "kdc:principals:read kdc:principals:write", time.Now().Add(time.Hour))
+	req := httptest.NewRequest(http.MethodPost, "/api/principals", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rw := httptest.NewRecorder()
+	handler(rw, req)
+
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rw.Code, rw.Body.String())
+	}
+	if gotClaims.Subject != "alice" {
+		t.Fatalf("expected subject alice, got %q", gotClaims.Subject)
+	}
+	if !gotClaims.HasScope("kdc:principals:write") {
+		t.Fatalf("expected the attached Claims to carry the required scope")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+	pb "github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service/pb"
+)
+
+// KDCGRPCServer exposes the exact same operations as KDCAPIServer, but over
+// gRPC instead of plain HTTP. Both servers are thin transports sitting on
+// top of the same *service.Service, so they share validation, DC/OS
+// reconnect and checksum logic by construction.
+type KDCGRPCServer struct {
+	pb.UnimplementedKDCServer
+
+	svc      *service.Service
+	endpoint string
+}
+
+func createKDCGRPCServer(kadmin *KAdminClient, port string, host string) *KDCGRPCServer {
+	return &KDCGRPCServer{
+		svc:      service.NewService(kadmin, dcosDial),
+		endpoint: fmt.Sprintf("%s:%s", host, port),
+	}
+}
+
+func (s *KDCGRPCServer) Start() error {
+	lis, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return fmt.Errorf("Unable to listen on %s: %s", s.endpoint, err.Error())
+	}
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterKDCServer(grpcServer, s)
+
+	log.Printf("Listening on %s (grpc)\n", s.endpoint)
+	return grpcServer.Serve(lis)
+}
+
+func toPBPrincipals(principals []KPrincipal) []*pb.Principal {
+	var out []*pb.Principal
+	for _, p := range principals {
+		out = append(out, &pb.Principal{
+			Realm:    p.Realm,
+			Primary:  p.Primary,
+			Instance: p.Instance,
+		})
+	}
+	return out
+}
+
+func fromPBPrincipals(principals []*pb.Principal) []KPrincipal {
+	var out []KPrincipal
+	for _, p := range principals {
+		out = append(out, KPrincipal{
+			Realm:    p.Realm,
+			Primary:  p.Primary,
+			Instance: p.Instance,
+		})
+	}
+	return out
+}
+
+func (s *KDCGRPCServer) AddPrincipals(ctx context.Context, req *pb.AddPrincipalsRequest) (*pb.AddPrincipalsReply, error) {
+	_, err := s.svc.AddPrincipals(service.AddPrincipalsRequest{
+		Principals: fromPBPrincipals(req.Principals),
+		Secret:     req.Secret,
+		Binary:     req.Binary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.AddPrincipalsReply{}, nil
+}
+
+func (s *KDCGRPCServer) ListPrincipals(ctx context.Context, req *pb.ListPrincipalsRequest) (*pb.ListPrincipalsReply, error) {
+	result, err := s.svc.ListPrincipals(service.ListPrincipalsRequest{
+		Filter: req.Filter,
+		Secret: req.Secret,
+		Binary: req.Binary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ListPrincipalsReply{
+		List:     toPBPrincipals(result.List),
+		Checksum: result.Checksum,
+	}, nil
+}
+
+func (s *KDCGRPCServer) DeletePrincipals(ctx context.Context, req *pb.DeletePrincipalsRequest) (*pb.DeletePrincipalsReply, error) {
+	_, err := s.svc.DeletePrincipals(service.DeletePrincipalsRequest{
+		Principals: fromPBPrincipals(req.Principals),
+		Secret:     req.Secret,
+		Binary:     req.Binary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.DeletePrincipalsReply{}, nil
+}
+
+func (s *KDCGRPCServer) CheckPrincipals(ctx context.Context, req *pb.CheckPrincipalsRequest) (*pb.CheckPrincipalsReply, error) {
+	result, err := s.svc.CheckPrincipals(service.CheckPrincipalsRequest{
+		Principals: fromPBPrincipals(req.Principals),
+		Secret:     req.Secret,
+		Binary:     req.Binary,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CheckPrincipalsReply{
+		Check: &pb.CheckStatus{
+			Pass:     result.Pass,
+			Reason:   result.Reason,
+			Checksum: result.Checksum,
+		},
+	}, nil
+}
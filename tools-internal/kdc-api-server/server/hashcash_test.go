@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// solve mutates a challenge's nonce until its sha1 hash has the required
+// number of leading zero bits, mimicking what a real client would do.
+func solve(t *testing.T, challenge string) string {
+	t.Helper()
+
+	prefix := challenge[:strings.LastIndex(challenge, ":")]
+	difficulty, err := strconv.Atoi(strings.SplitN(challenge, ":", 4)[1])
+	if err != nil {
+		t.Fatalf("unable to parse difficulty from challenge: %s", err)
+	}
+
+	for i := 0; i < 1_000_000; i++ {
+		stamp := fmt.Sprintf("%s%d:", prefix, i)
+		sum := sha1.Sum([]byte(stamp))
+		if leadingZeroBits(sum[:], difficulty) {
+			return stamp
+		}
+	}
+
+	t.Fatalf("failed to solve challenge %q within the iteration budget", challenge)
+	return ""
+}
+
+func TestHashcashGateRoundTrip(t *testing.T) {
+	gate := newHashcashGate(4, time.Minute)
+
+	challenge, err := gate.challenge("/api/principals:abc123")
+	if err != nil {
+		t.Fatalf("challenge() returned an error: %s", err)
+	}
+
+	stamp := solve(t, challenge)
+
+	if err := gate.verify(stamp, "/api/principals:abc123"); err != nil {
+		t.Fatalf("verify() rejected a validly solved stamp: %s", err)
+	}
+}
+
+func TestHashcashGateRejectsReplay(t *testing.T) {
+	gate := newHashcashGate(4, time.Minute)
+
+	challenge, err := gate.challenge("/api/principals:abc123")
+	if err != nil {
+		t.Fatalf("challenge() returned an error: %s", err)
+	}
+	stamp := solve(t, challenge)
+
+	if err := gate.verify(stamp, "/api/principals:abc123"); err != nil {
+		t.Fatalf("first verify() should have succeeded: %s", err)
+	}
+	if err := gate.verify(stamp, "/api/principals:abc123"); err == nil {
+		t.Fatalf("second verify() of the same stamp should have been rejected as a replay")
+	}
+}
+
+func TestHashcashGateRejectsWrongResource(t *testing.T) {
+	gate := newHashcashGate(4, time.Minute)
+
+	challenge, err := gate.challenge("/api/principals:abc123")
+	if err != nil {
+		t.Fatalf("challenge() returned an error: %s", err)
+	}
+	stamp := solve(t, challenge)
+
+	if err := gate.verify(stamp, "/api/principals:def456"); err == nil {
+		t.Fatalf("verify() should reject a stamp minted for a different resource")
+	}
+}
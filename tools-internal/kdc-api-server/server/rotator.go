@@ -0,0 +1,302 @@
+package main
+
+import (
+	"container/ring"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+// KeytabRotator periodically re-keys principals already known to KDC and
+// republishes their keytabs to the DC/OS secret store, modelled on
+// ACME-style automated credential renewal. Rotations for a given secret
+// are serialized with a keyed mutex so two overlapping rotations (one
+// scheduled, one triggered via the API) can never race each other's
+// writes.
+type KeytabRotator struct {
+	kadmin *KAdminClient
+
+	interval time.Duration
+	jitter   time.Duration
+
+	locksMu sync.Mutex
+	locks   map[string]*sync.Mutex
+
+	historyMu sync.Mutex
+	history   map[string]*ring.Ring // secret -> ring buffer of rotationRecord
+
+	historySize int
+}
+
+type rotationRecord struct {
+	Secret    string    `json:"secret"`
+	Kvno      int       `json:"kvno"`
+	Checksum  string    `json:"checksum"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+func NewKeytabRotator(kadmin *KAdminClient, interval time.Duration, jitter time.Duration) *KeytabRotator {
+	return &KeytabRotator{
+		kadmin:      kadmin,
+		interval:    interval,
+		jitter:      jitter,
+		locks:       make(map[string]*sync.Mutex),
+		history:     make(map[string]*ring.Ring),
+		historySize: 50,
+	}
+}
+
+func (r *KeytabRotator) lockFor(secret string) *sync.Mutex {
+	r.locksMu.Lock()
+	defer r.locksMu.Unlock()
+	if l, ok := r.locks[secret]; ok {
+		return l
+	}
+	l := &sync.Mutex{}
+	r.locks[secret] = l
+	return l
+}
+
+// ScheduleSecret runs an indefinite rotation loop for a single secret/
+// principals/binary tuple, honouring the configured interval and jitter,
+// until stopCh is closed.
+func (r *KeytabRotator) ScheduleSecret(principals []KPrincipal, secret string, binary bool, stopCh <-chan struct{}) {
+	for {
+		wait := r.interval
+		if r.jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(r.jitter)))
+		}
+
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(wait):
+		}
+
+		if err := r.Rotate(principals, secret, binary); err != nil {
+			// Rotation failures are retried on the next tick; the caller is
+			// expected to monitor logs/metrics for repeated failures.
+			continue
+		}
+	}
+}
+
+// Rotate bumps the kvno for principals, exports a fresh keytab, uploads it
+// to secret, and keeps a copy at "<secret>-v{kvno}" so Rollback can restore
+// the previous generation.
+func (r *KeytabRotator) Rotate(principals []KPrincipal, secret string, binary bool) error {
+	lock := r.lockFor(secret)
+	lock.Lock()
+	defer lock.Unlock()
+
+	for _, principal := range principals {
+		if err := r.kadmin.ChangePassword(principal); err != nil {
+			return fmt.Errorf("Unable to rotate principal %s: %s", principal.Full(), err.Error())
+		}
+	}
+
+	keytab, err := r.kadmin.GetKeytabForPrincipals(principals)
+	if err != nil {
+		return fmt.Errorf("Unable to export keytab: %s", err.Error())
+	}
+
+	kvno, err := r.kadmin.CurrentKvno(principals)
+	if err != nil {
+		return fmt.Errorf("Unable to determine kvno: %s", err.Error())
+	}
+
+	dclient, err := dcosDial()
+	if err != nil {
+		return fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	versionedSecret := fmt.Sprintf("%s-v%d", secret, kvno)
+	if err := dclient.CreateKeytabSecret(versionedSecret, keytab, binary); err != nil {
+		return fmt.Errorf("Unable to upload rollback copy to %s: %s", versionedSecret, err.Error())
+	}
+	if err := dclient.CreateKeytabSecret(secret, keytab, binary); err != nil {
+		return fmt.Errorf("Unable to upload to secret store: %s", err.Error())
+	}
+
+	checksum, err := service.GetPrincipalsChecksum(keytab, principals)
+	if err != nil {
+		return err
+	}
+
+	r.recordHistory(secret, rotationRecord{
+		Secret:    secret,
+		Kvno:      kvno,
+		Checksum:  checksum,
+		RotatedAt: time.Now(),
+	})
+
+	return nil
+}
+
+// Rollback copies "<secret>-v{kvno}" back into secret.
+func (r *KeytabRotator) Rollback(secret string, kvno int, binary bool) error {
+	lock := r.lockFor(secret)
+	lock.Lock()
+	defer lock.Unlock()
+
+	dclient, err := dcosDial()
+	if err != nil {
+		return fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	versionedSecret := fmt.Sprintf("%s-v%d", secret, kvno)
+	keytab, err := dclient.GetKeytabSecret(versionedSecret, binary)
+	if err != nil {
+		return fmt.Errorf("Unable to read %s: %s", versionedSecret, err.Error())
+	}
+	if keytab == nil {
+		return fmt.Errorf("No rollback copy found at %s", versionedSecret)
+	}
+
+	return dclient.CreateKeytabSecret(secret, keytab, binary)
+}
+
+func (r *KeytabRotator) recordHistory(secret string, rec rotationRecord) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	buf, ok := r.history[secret]
+	if !ok {
+		buf = ring.New(r.historySize)
+		r.history[secret] = buf
+	}
+	buf.Value = rec
+	r.history[secret] = buf.Next()
+}
+
+func (r *KeytabRotator) History(secret string) []rotationRecord {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	buf, ok := r.history[secret]
+	if !ok {
+		return nil
+	}
+
+	var out []rotationRecord
+	buf.Do(func(v interface{}) {
+		if rec, ok := v.(rotationRecord); ok {
+			out = append(out, rec)
+		}
+	})
+	return out
+}
+
+type rotateRequest struct {
+	Principals []KPrincipal `json:"principals"`
+	Secret     string       `json:"secret"`
+	Binary     *bool        `json:"binary"`
+}
+
+// handleRotate handles POST /api/principals/rotate.
+func (s *KDCAPIServer) handleRotate(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		s.replyReject(rw, req, `Accepting only POST requests on this endpoint`)
+		return
+	}
+
+	var apiReq rotateRequest
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&apiReq); err == io.EOF {
+		s.replyReject(rw, req, `Could not decode input`)
+		return
+	} else if err != nil {
+		s.replyReject(rw, req, `Unable to parse request: %s`, err.Error())
+		return
+	}
+
+	if len(apiReq.Principals) == 0 {
+		s.replyReject(rw, req, `given an empty list of principals`)
+		return
+	}
+
+	useBinary := false
+	if apiReq.Binary != nil {
+		useBinary = *apiReq.Binary
+	}
+
+	if err := s.rotator.Rotate(apiReq.Principals, apiReq.Secret, useBinary); err != nil {
+		s.replyReject(rw, req, `%s`, err.Error())
+		return
+	}
+
+	s.dispatcher.Emit(Event{
+		Type:       EventKeytabUpdated,
+		Timestamp:  time.Now(),
+		Secret:     apiReq.Secret,
+		Principals: apiReq.Principals,
+	})
+
+	s.replySuccess(rw, req, nil)
+}
+
+// handleRotateHistory handles GET /api/principals/history?secret=....
+func (s *KDCAPIServer) handleRotateHistory(rw http.ResponseWriter, req *http.Request) {
+	secret := req.URL.Query().Get("secret")
+	if secret == "" {
+		s.replyReject(rw, req, `missing 'secret=' argument`)
+		return
+	}
+
+	js, err := json.Marshal(s.rotator.History(secret))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(js)
+}
+
+type rollbackRequest struct {
+	Secret string `json:"secret"`
+	Kvno   int    `json:"kvno"`
+	Binary *bool  `json:"binary"`
+}
+
+// handleRollback handles POST /api/principals/rollback.
+func (s *KDCAPIServer) handleRollback(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		s.replyReject(rw, req, `Accepting only POST requests on this endpoint`)
+		return
+	}
+
+	var apiReq rollbackRequest
+	dec := json.NewDecoder(req.Body)
+	if err := dec.Decode(&apiReq); err == io.EOF {
+		s.replyReject(rw, req, `Could not decode input`)
+		return
+	} else if err != nil {
+		s.replyReject(rw, req, `Unable to parse request: %s`, err.Error())
+		return
+	}
+
+	useBinary := false
+	if apiReq.Binary != nil {
+		useBinary = *apiReq.Binary
+	}
+
+	if err := s.rotator.Rollback(apiReq.Secret, apiReq.Kvno, useBinary); err != nil {
+		s.replyReject(rw, req, `%s`, err.Error())
+		return
+	}
+
+	s.dispatcher.Emit(Event{
+		Type:      EventKeytabUpdated,
+		Timestamp: time.Now(),
+		Secret:    apiReq.Secret,
+	})
+
+	s.replySuccess(rw, req, nil)
+}
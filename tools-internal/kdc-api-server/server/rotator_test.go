@@ -0,0 +1,69 @@
+package main
+
+import (
+	"container/ring"
+	"testing"
+	"time"
+)
+
+// newTestRotator builds a KeytabRotator with just enough state to exercise
+// recordHistory/History - the kadmin/dcosDial-backed Rotate/Rollback paths
+// need a live KAdminClient and are exercised against a real KDC elsewhere.
+func newTestRotator(historySize int) *KeytabRotator {
+	return &KeytabRotator{
+		history:     make(map[string]*ring.Ring),
+		historySize: historySize,
+	}
+}
+
+func TestKeytabRotatorHistoryOrdering(t *testing.T) {
+	r := newTestRotator(50)
+
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 1, RotatedAt: time.Unix(1, 0)})
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 2, RotatedAt: time.Unix(2, 0)})
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 3, RotatedAt: time.Unix(3, 0)})
+
+	history := r.History("kdc/keytab")
+	if len(history) != 3 {
+		t.Fatalf("expected 3 history entries, got %d", len(history))
+	}
+	for i, want := range []int{1, 2, 3} {
+		if history[i].Kvno != want {
+			t.Errorf("history[%d].Kvno = %d, want %d", i, history[i].Kvno, want)
+		}
+	}
+}
+
+func TestKeytabRotatorHistoryEvictsOldest(t *testing.T) {
+	r := newTestRotator(2)
+
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 1})
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 2})
+	r.recordHistory("kdc/keytab", rotationRecord{Secret: "kdc/keytab", Kvno: 3})
+
+	history := r.History("kdc/keytab")
+	if len(history) != 2 {
+		t.Fatalf("expected the oldest entry to be evicted, leaving 2, got %d", len(history))
+	}
+	if history[0].Kvno != 2 || history[1].Kvno != 3 {
+		t.Fatalf("expected kvnos [2 3] after eviction, got [%d %d]", history[0].Kvno, history[1].Kvno)
+	}
+}
+
+func TestKeytabRotatorHistoryPerSecret(t *testing.T) {
+	r := newTestRotator(50)
+
+	r.recordHistory("kdc/a", rotationRecord{Secret: "kdc/a", Kvno: 1})
+	r.recordHistory("kdc/b", rotationRecord{Secret: "kdc/b", Kvno: 1})
+	r.recordHistory("kdc/a", rotationRecord{Secret: "kdc/a", Kvno: 2})
+
+	if got := r.History("kdc/a"); len(got) != 2 {
+		t.Fatalf("expected 2 entries for kdc/a, got %d", len(got))
+	}
+	if got := r.History("kdc/b"); len(got) != 1 {
+		t.Fatalf("expected 1 entry for kdc/b, got %d", len(got))
+	}
+	if got := r.History("kdc/unknown"); got != nil {
+		t.Fatalf("expected nil history for a secret never rotated, got %v", got)
+	}
+}
@@ -1,21 +1,47 @@
 package main
 
 import (
-	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"sort"
 	"strings"
+	"time"
 
-	"gopkg.in/jcmturner/gokrb5.v7/keytab"
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
 )
 
+// KPrincipal is kept as an alias of service.KPrincipal so that the rest of
+// this package (and KAdminClient in particular) can go on referring to
+// KPrincipal without change, while the service layer owns the canonical
+// definition.
+type KPrincipal = service.KPrincipal
+
 type KDCAPIServer struct {
-	kadmin   *KAdminClient
-	endpoint string
+	kadmin         *KAdminClient
+	svc            *service.Service
+	endpoint       string
+	addHashcash    *hashcashGate
+	deleteHashcash *hashcashGate
+	auth           *jwksAuthenticator
+	rotator        *KeytabRotator
+	dispatcher     *Dispatcher
+}
+
+const (
+	scopePrincipalsRead  = "kdc:principals:read"
+	scopePrincipalsWrite = "kdc:principals:write"
+	scopeCheck           = "kdc:check"
+)
+
+// HashcashConfig controls the optional proof-of-work gate in front of the
+// mutating /api/principals operations. A zero difficulty disables the
+// gate for that endpoint.
+type HashcashConfig struct {
+	AddDifficulty    int
+	DeleteDifficulty int
+	TTL              time.Duration
 }
 
 type KDCRequestAddPrincipal struct {
@@ -49,45 +75,33 @@ type KDCResponse struct {
 	Check      *KDCCheckStatus            `json:"check,omitempty"`
 }
 
-/**
- * Sortable list of principals
- */
-type KPrincipalList []KPrincipal
-
-func (p KPrincipalList) Len() int {
-	return len(p)
-}
-func (p KPrincipalList) Less(i, j int) bool {
-	return p[i].Full() < p[j].Full()
-}
-func (p KPrincipalList) Swap(i, j int) {
-	p[i], p[j] = p[j], p[i]
+// RotateConfig controls the automated keytab rotation scheduler.
+type RotateConfig struct {
+	Interval time.Duration
+	Jitter   time.Duration
 }
 
-type KTKey struct {
-	Type         int32
-	HashContents string
-}
+func createKDCAPIServer(kadmin *KAdminClient, port string, host string, hashcashCfg HashcashConfig, authCfg AuthConfig, rotateCfg RotateConfig, webhookSinks []webhookSink) (*KDCAPIServer, error) {
+	if hashcashCfg.TTL <= 0 {
+		hashcashCfg.TTL = 5 * time.Minute
+	}
 
-/**
- * Sortable list of encryption keys
- */
-type KTKeyList []KTKey
+	auth, err := newJWKSAuthenticator(authCfg)
+	if err != nil {
+		return nil, err
+	}
 
-func (p KTKeyList) Len() int {
-	return len(p)
-}
-func (p KTKeyList) Less(i, j int) bool {
-	return p[i].Type < p[j].Type
-}
-func (p KTKeyList) Swap(i, j int) {
-	p[i], p[j] = p[j], p[i]
-}
+	svc := service.NewService(kadmin, dcosDial)
 
-func createKDCAPIServer(kadmin *KAdminClient, port string, host string) *KDCAPIServer {
 	inst := &KDCAPIServer{
-		kadmin:   kadmin,
-		endpoint: fmt.Sprintf("%s:%s", host, port),
+		kadmin:         kadmin,
+		svc:            svc,
+		endpoint:       fmt.Sprintf("%s:%s", host, port),
+		addHashcash:    newHashcashGate(hashcashCfg.AddDifficulty, hashcashCfg.TTL),
+		deleteHashcash: newHashcashGate(hashcashCfg.DeleteDifficulty, hashcashCfg.TTL),
+		auth:           auth,
+		rotator:        NewKeytabRotator(kadmin, rotateCfg.Interval, rotateCfg.Jitter),
+		dispatcher:     NewDispatcher(webhookSinks, 256),
 	}
 
 	// Register the static API server
@@ -99,8 +113,23 @@ func createKDCAPIServer(kadmin *KAdminClient, port string, host string) *KDCAPIS
 
 	// Register the app routes
 	http.HandleFunc("/api/principals", inst.handlePrincipals)
-	http.HandleFunc("/api/check", inst.handleCheckPrincipals)
-	return inst
+	http.HandleFunc("/api/check", inst.auth.requireScope(scopeCheck, inst.handleCheckPrincipals))
+	http.HandleFunc("/api/new-hashcash", inst.handleNewHashcash)
+	http.HandleFunc("/api/principals/rotate", inst.auth.requireScope(scopePrincipalsWrite, inst.handleRotate))
+	http.HandleFunc("/api/principals/history", inst.auth.requireScope(scopePrincipalsRead, inst.handleRotateHistory))
+	http.HandleFunc("/api/principals/rollback", inst.auth.requireScope(scopePrincipalsWrite, inst.handleRollback))
+	http.HandleFunc("/api/webhooks/deliveries", inst.auth.requireScope(scopePrincipalsRead, inst.handleWebhookDeliveries))
+	return inst, nil
+}
+
+// handleNewHashcash issues a challenge for the "add" or "delete" mutating
+// endpoints, selected via ?op=add|delete (defaults to "add").
+func (s *KDCAPIServer) handleNewHashcash(rw http.ResponseWriter, req *http.Request) {
+	gate := s.addHashcash
+	if req.URL.Query().Get("op") == "delete" {
+		gate = s.deleteHashcash
+	}
+	gate.handleNewHashcash(rw, req)
 }
 
 func (s *KDCAPIServer) Start() {
@@ -187,73 +216,49 @@ func (s *KDCAPIServer) replySuccess(rw http.ResponseWriter, req *http.Request, d
 	}
 }
 
-/**
- * getPrincipalsChecksum calculates a checksum with the contents of the keys
- * of all the principals given, as found in the keytab given.
- */
-func getPrincipalsChecksum(keytabBytes []byte, principals []KPrincipal) (string, error) {
-	var kt keytab.Keytab
-	var principalKeys map[string]KTKeyList = make(map[string]KTKeyList)
-	var sortedPrincipals KPrincipalList = nil
-	var csumContents string = ""
-
-	// Parse the keytab contents and create a sorted list of keys for each principal
-	err := kt.Unmarshal(keytabBytes)
-	if err != nil {
-		return "", fmt.Errorf(`Unable to parse keytab contents: %s`, err.Error())
-	}
-	for _, entry := range kt.Entries {
-		var p KPrincipal
-		p.Realm = entry.Principal.Realm
-		if len(entry.Principal.Components) > 0 {
-			p.Primary = entry.Principal.Components[0]
-		}
-		if len(entry.Principal.Components) > 1 {
-			p.Instance = entry.Principal.Components[1]
-		}
-
-		var k KTKey
-		k.Type = entry.Key.KeyType
-		k.HashContents = fmt.Sprintf("%d:%x", entry.Key.KeyType, entry.Key.KeyValue)
-
-		var list KTKeyList = nil
-		if l, ok := principalKeys[p.Full()]; ok {
-			list = l
-		} else {
-			list = nil
-		}
+// dcosClientFuncs adapts the connection returned by
+// createDCOSClientFromEnvironment - together with the package-level
+// CreateKeytabSecret/GetKeytabSecret/DeleteKeytabSecret helpers - to the
+// service.DCOSClient interface expected by the service layer.
+type dcosClientFuncs struct {
+	createFn func(name string, keytab []byte, binary bool) error
+	getFn    func(name string, binary bool) ([]byte, error)
+	deleteFn func(name string, binary bool) error
+}
 
-		list = append(list, k)
-		sort.Sort(list)
-		principalKeys[p.Full()] = list
-	}
+func (a dcosClientFuncs) CreateKeytabSecret(name string, keytab []byte, binary bool) error {
+	return a.createFn(name, keytab, binary)
+}
 
-	// Create a sorted list of principals and calculate a unique checksum
-	// in the order they appear
-	for _, principal := range principals {
-		sortedPrincipals = append(sortedPrincipals, principal)
-	}
-	sort.Sort(sortedPrincipals)
-	for _, principal := range sortedPrincipals {
-		// If the entry was not found, raise an error since in the previous steps
-		// we made sure that the principal should be included in the keytab
-		keyList, ok := principalKeys[principal.Full()]
-		if !ok {
-			return "", fmt.Errorf("Could not located principal '%s' in the parsed keytab", principal.Full())
-		}
+func (a dcosClientFuncs) GetKeytabSecret(name string, binary bool) ([]byte, error) {
+	return a.getFn(name, binary)
+}
 
-		// Include the keys of this principal
-		for _, key := range keyList {
-			if csumContents != "" {
-				csumContents += ","
-			}
-			csumContents += key.HashContents
-		}
-	}
+func (a dcosClientFuncs) DeleteKeytabSecret(name string, binary bool) error {
+	return a.deleteFn(name, binary)
+}
 
-	// Hash the checksum
-	sum := sha256.Sum256([]byte(csumContents))
-	return fmt.Sprintf("%x", sum), nil
+// dcosDial re-connects to DC/OS using the environment-provided credentials
+// and wraps the resulting client so the service layer can use it through
+// service.DCOSClient. It is called once per request, since the downstream
+// DC/OS token can expire at any time and the login procedure is cheap.
+func dcosDial() (service.DCOSClient, error) {
+	dclient, err := createDCOSClientFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	return dcosClientFuncs{
+		createFn: func(name string, keytab []byte, binary bool) error {
+			return CreateKeytabSecret(dclient, name, keytab, binary)
+		},
+		getFn: func(name string, binary bool) ([]byte, error) {
+			return GetKeytabSecret(dclient, name, binary)
+		},
+		deleteFn: func(name string, binary bool) error {
+			return DeleteKeytabSecret(dclient, name, binary)
+		},
+	}, nil
 }
 
 /**
@@ -262,11 +267,11 @@ func getPrincipalsChecksum(keytabBytes []byte, principals []KPrincipal) (string,
 func (s *KDCAPIServer) handlePrincipals(rw http.ResponseWriter, req *http.Request) {
 	switch req.Method {
 	case "POST":
-		s.handleAddPrincipal(rw, req)
+		s.auth.requireScope(scopePrincipalsWrite, s.handleAddPrincipal)(rw, req)
 	case "GET":
-		s.handleListPrincipals(rw, req)
+		s.auth.requireScope(scopePrincipalsRead, s.handleListPrincipals)(rw, req)
 	case "DELETE":
-		s.handleDeletePrincipals(rw, req)
+		s.auth.requireScope(scopePrincipalsWrite, s.handleDeletePrincipals)(rw, req)
 	default:
 		s.replyReject(rw, req, `Accepting only POST/GET/DELETE requests on this endpoint`)
 	}
@@ -330,30 +335,8 @@ func (s *KDCAPIServer) handleAddPrincipal(rw http.ResponseWriter, req *http.Requ
 
 	}
 
-	if len(apiReq.Principals) == 0 {
-		s.replyReject(rw, req, `given an empty list of principals`)
-		return
-	}
-
-	// Since our auth token can expire at any time, we are re-connecting on
-	// DC/OS on every request. Since we are not expecting any serious request
-	// rate on this endpoint,  and since the log-in procedure is quite fast
-	// we should be OK
-	dclient, err := createDCOSClientFromEnvironment()
-	if err != nil {
-		s.replyReject(rw, req, `Unable to connect to DC/OS: %s`, err.Error())
-		return
-	}
-
-	err = s.kadmin.AddMissingPrincipals(apiReq.Principals)
-	if err != nil {
-		s.replyReject(rw, req, `Unable to add principals: %s`, err.Error())
-		return
-	}
-
-	keytab, err := s.kadmin.GetKeytabForPrincipals(apiReq.Principals)
-	if err != nil {
-		s.replyReject(rw, req, `Unable to export keytab: %s`, err.Error())
+	if err := s.addHashcash.checkRequest(req, req.URL.Path, apiReq.Principals); err != nil {
+		s.replyReject(rw, req, `%s`, err.Error())
 		return
 	}
 
@@ -362,12 +345,23 @@ func (s *KDCAPIServer) handleAddPrincipal(rw http.ResponseWriter, req *http.Requ
 		useBinary = *apiReq.Binary
 	}
 
-	err = CreateKeytabSecret(dclient, apiReq.Secret, keytab, useBinary)
+	_, err := s.svc.AddPrincipals(service.AddPrincipalsRequest{
+		Principals: apiReq.Principals,
+		Secret:     apiReq.Secret,
+		Binary:     useBinary,
+	})
 	if err != nil {
-		s.replyReject(rw, req, `Unable to upload to secret store: %s`, err.Error())
+		s.replyReject(rw, req, `%s`, err.Error())
 		return
 	}
 
+	s.dispatcher.Emit(Event{
+		Type:       EventPrincipalAdded,
+		Timestamp:  time.Now(),
+		Secret:     apiReq.Secret,
+		Principals: apiReq.Principals,
+	})
+
 	// We are done
 	s.replySuccess(rw, req, nil)
 }
@@ -409,64 +403,23 @@ func (s *KDCAPIServer) handleListPrincipals(rw http.ResponseWriter, req *http.Re
 		}
 	}
 
-	list, err := s.kadmin.ListPrincipals(filterExpr.Filter)
-	if err != nil {
-		s.replyReject(rw, req, `Unable to list principals: %s`, err.Error())
-		return
+	useBinary := false
+	if filterExpr.Binary != nil {
+		useBinary = *filterExpr.Binary
 	}
 
-	// If there was a secret argument in the query, strip-out principals
-	// not present in the secret file given
-	if filterExpr.Secret != "" {
-		// Since our auth token can expire at any time, we are re-connecting on
-		// DC/OS on every request. Since we are not expecting any serious request
-		// rate on this endpoint,  and since the log-in procedure is quite fast
-		// we should be OK
-		dclient, err := createDCOSClientFromEnvironment()
-		if err != nil {
-			s.replyReject(rw, req, `Unable to connect to DC/OS: %s`, err.Error())
-			return
-		}
-
-		useBinary := false
-		if filterExpr.Binary != nil {
-			useBinary = *filterExpr.Binary
-		}
-
-		ktBytes, err := GetKeytabSecret(dclient, filterExpr.Secret, useBinary)
-		if err != nil {
-			s.replyReject(rw, req, `Unable to read the keytab secret: %s`, err.Error())
-			return
-		}
-
-		if ktBytes == nil {
-			s.replyReject(rw, req, `The secret was empty`)
-			return
-		}
-
-		// Filter-out missing principals
-		var newList []KPrincipal = nil
-		for _, principal := range list {
-			ok, err := s.kadmin.HasPrincipalInKeytab(ktBytes, &principal)
-			if err != nil {
-				s.replyReject(rw, req, `Unable to check if principal %s exists in keytab: %s`, principal.Full(), err.Error())
-				return
-			}
-			if ok {
-				newList = append(newList, principal)
-			}
-		}
-		list = newList
-
-		csum, err := getPrincipalsChecksum(ktBytes, list)
-		if err != nil {
-			s.replyReject(rw, req, err.Error())
-			return
-		}
-		resp.Checksum = csum
+	result, err := s.svc.ListPrincipals(service.ListPrincipalsRequest{
+		Filter: filterExpr.Filter,
+		Secret: filterExpr.Secret,
+		Binary: useBinary,
+	})
+	if err != nil {
+		s.replyReject(rw, req, `%s`, err.Error())
+		return
 	}
 
-	resp.List = list
+	resp.List = result.List
+	resp.Checksum = result.Checksum
 	s.replySuccess(rw, req, &resp)
 }
 
@@ -533,32 +486,32 @@ func (s *KDCAPIServer) handleDeletePrincipals(rw http.ResponseWriter, req *http.
 		return
 	}
 
+	if err := s.deleteHashcash.checkRequest(req, req.URL.Path, apiReq.Principals); err != nil {
+		s.replyReject(rw, req, `%s`, err.Error())
+		return
+	}
+
 	useBinary := false
 	if apiReq.Binary != nil {
 		useBinary = *apiReq.Binary
 	}
 
-	// Since our auth token can expire at any time, we are re-connecting on
-	// DC/OS on every request. Since we are not expecting any serious request
-	// rate on this endpoint,  and since the log-in procedure is quite fast
-	// we should be OK
-	dclient, err := createDCOSClientFromEnvironment()
-	if err != nil {
-		s.replyReject(rw, req, `Unable to connect to DC/OS: %s`, err.Error())
-		return
-	}
-
-	err = DeleteKeytabSecret(dclient, apiReq.Secret, useBinary)
+	_, err := s.svc.DeletePrincipals(service.DeletePrincipalsRequest{
+		Principals: apiReq.Principals,
+		Secret:     apiReq.Secret,
+		Binary:     useBinary,
+	})
 	if err != nil {
-		s.replyReject(rw, req, `Unable to delete secret: %s`, err.Error())
+		s.replyReject(rw, req, `%s`, err.Error())
 		return
 	}
 
-	err = s.kadmin.DeletePrincipals(apiReq.Principals)
-	if err != nil {
-		s.replyReject(rw, req, `Unable to delete principals: %s`, err.Error())
-		return
-	}
+	s.dispatcher.Emit(Event{
+		Type:       EventPrincipalDeleted,
+		Timestamp:  time.Now(),
+		Secret:     apiReq.Secret,
+		Principals: apiReq.Principals,
+	})
 
 	s.replySuccess(rw, req, nil)
 }
@@ -630,73 +583,29 @@ func (s *KDCAPIServer) handleCheckPrincipals(rw http.ResponseWriter, req *http.R
 		return
 	}
 
-	// Before continuing with validating the secret, make sure that all the
-	// principals are present in KDC
-	for _, principal := range apiReq.Principals {
-		ok, err := s.kadmin.HasPrincipal(principal)
-		if err != nil {
-			s.replyReject(rw, req, `Unable to check if principal %s exists: %s`, principal.Full(), err.Error())
-			return
-		}
-		if !ok {
-			// We don't have a required principal -> check failed
-			s.replySuccess(rw, req, &KDCCheckStatus{
-				false, fmt.Sprintf("Principal '%s' does not exist in kerberos", principal.Full()), "",
-			})
-			return
-		}
-	}
-
-	// Since our auth token can expire at any time, we are re-connecting on
-	// DC/OS on every request. Since we are not expecting any serious request
-	// rate on this endpoint,  and since the log-in procedure is quite fast
-	// we should be OK
-	dclient, err := createDCOSClientFromEnvironment()
-	if err != nil {
-		s.replyReject(rw, req, `Unable to connect to DC/OS: %s`, err.Error())
-		return
-	}
-
 	useBinary := false
 	if apiReq.Binary != nil {
 		useBinary = *apiReq.Binary
 	}
 
-	ktBytes, err := GetKeytabSecret(dclient, apiReq.Secret, useBinary)
+	result, err := s.svc.CheckPrincipals(service.CheckPrincipalsRequest{
+		Principals: apiReq.Principals,
+		Secret:     apiReq.Secret,
+		Binary:     useBinary,
+	})
 	if err != nil {
-		s.replyReject(rw, req, `Unable to read the keytab secret: %s`, err.Error())
+		s.replyReject(rw, req, `%s`, err.Error())
 		return
 	}
 
-	// If the secret is empty, fail the check
-	if ktBytes == nil {
-		s.replySuccess(rw, req, &KDCCheckStatus{
-			false, fmt.Sprintf("Secret '%s' does not exist", apiReq.Secret), "",
+	if !result.Pass {
+		s.dispatcher.Emit(Event{
+			Type:       EventCheckFailed,
+			Timestamp:  time.Now(),
+			Secret:     apiReq.Secret,
+			Principals: apiReq.Principals,
 		})
-		return
-	}
-
-	// Check if the requested principals do not exist
-	for _, principal := range apiReq.Principals {
-		ok, err := s.kadmin.HasPrincipalInKeytab(ktBytes, &principal)
-		if err != nil {
-			s.replyReject(rw, req, `Unable to check if principal %s exists in keytab: %s`, principal.Full(), err.Error())
-			return
-		}
-		if !ok {
-			// We don't have a required principal in the keytab -> check failed
-			s.replySuccess(rw, req, &KDCCheckStatus{
-				false, fmt.Sprintf("Principal '%s' does not exist in keytab", principal.Full()), "",
-			})
-			return
-		}
-	}
-
-	csum, err := getPrincipalsChecksum(ktBytes, apiReq.Principals)
-	if err != nil {
-		s.replyReject(rw, req, err.Error())
-		return
 	}
 
-	s.replySuccess(rw, req, &KDCCheckStatus{true, "", csum})
+	s.replySuccess(rw, req, &KDCCheckStatus{result.Pass, result.Reason, result.Checksum})
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mesosphere/dcos-commons/tools-internal/kdc-api-server/service"
+)
+
+// Event is emitted whenever a principal or keytab lifecycle change
+// happens, so external systems can react without polling /api/principals.
+type Event struct {
+	Type       string               `json:"type"`
+	Timestamp  time.Time            `json:"timestamp"`
+	Secret     string               `json:"secret"`
+	Principals []service.KPrincipal `json:"principals"`
+	Checksum   string               `json:"checksum,omitempty"`
+}
+
+const (
+	EventPrincipalAdded   = "principal.added"
+	EventPrincipalDeleted = "principal.deleted"
+	EventKeytabUpdated    = "keytab.updated"
+	EventCheckFailed      = "check.failed"
+)
+
+// delivery records the outcome of one attempt to deliver an Event to a
+// sink, for GET /api/webhooks/deliveries.
+type delivery struct {
+	URL         string    `json:"url"`
+	Event       Event     `json:"event"`
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// webhookJob is a single (sink, event) pair waiting in the retry queue.
+type webhookJob struct {
+	url     string
+	secret  string
+	event   Event
+	attempt int
+}
+
+// Dispatcher fans Events out to every configured sink as signed JSON POSTs,
+// retrying failed deliveries with exponential backoff. The queue is
+// bounded and drops the oldest pending job on overflow rather than
+// blocking the caller that emitted the event.
+type Dispatcher struct {
+	sinks []webhookSink
+
+	queue  chan webhookJob
+	client *http.Client
+	maxTry int
+
+	mu         sync.Mutex
+	deliveries []delivery
+	maxHistory int
+}
+
+type webhookSink struct {
+	URL    string
+	Secret string
+}
+
+func NewDispatcher(sinks []webhookSink, queueSize int) *Dispatcher {
+	d := &Dispatcher{
+		sinks:      sinks,
+		queue:      make(chan webhookJob, queueSize),
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxTry:     5,
+		maxHistory: 200,
+	}
+	go d.drain()
+	return d
+}
+
+// Emit queues ev for delivery to every configured sink. If the queue is
+// full, the oldest pending job is dropped to make room, so a burst of
+// events cannot block the handler that produced them.
+func (d *Dispatcher) Emit(ev Event) {
+	for _, sink := range d.sinks {
+		job := webhookJob{url: sink.URL, secret: sink.Secret, event: ev}
+		select {
+		case d.queue <- job:
+		default:
+			select {
+			case <-d.queue:
+			default:
+			}
+			select {
+			case d.queue <- job:
+			default:
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) drain() {
+	for job := range d.queue {
+		d.deliver(job)
+	}
+}
+
+func (d *Dispatcher) deliver(job webhookJob) {
+	job.attempt++
+
+	body, err := json.Marshal(job.event)
+	if err != nil {
+		d.record(delivery{URL: job.url, Event: job.event, Attempt: job.attempt, Error: err.Error(), DeliveredAt: time.Now()})
+		return
+	}
+
+	req, err := http.NewRequest("POST", job.url, bytes.NewReader(body))
+	if err != nil {
+		d.record(delivery{URL: job.url, Event: job.event, Attempt: job.attempt, Error: err.Error(), DeliveredAt: time.Now()})
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-KDC-Signature", "sha256="+signBody(job.secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil || resp.StatusCode >= 300 {
+		rec := delivery{URL: job.url, Event: job.event, Attempt: job.attempt, DeliveredAt: time.Now()}
+		if err != nil {
+			rec.Error = err.Error()
+		} else {
+			rec.StatusCode = resp.StatusCode
+			rec.Error = fmt.Sprintf("sink responded with status %d", resp.StatusCode)
+			resp.Body.Close()
+		}
+		d.record(rec)
+
+		if job.attempt < d.maxTry {
+			backoff := time.Duration(1<<uint(job.attempt)) * time.Second
+			time.AfterFunc(backoff, func() {
+				select {
+				case d.queue <- job:
+				default:
+				}
+			})
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	d.record(delivery{URL: job.url, Event: job.event, Attempt: job.attempt, StatusCode: resp.StatusCode, DeliveredAt: time.Now()})
+}
+
+func signBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) record(rec delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.deliveries = append(d.deliveries, rec)
+	if len(d.deliveries) > d.maxHistory {
+		d.deliveries = d.deliveries[len(d.deliveries)-d.maxHistory:]
+	}
+}
+
+func (d *Dispatcher) Deliveries() []delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	out := make([]delivery, len(d.deliveries))
+	copy(out, d.deliveries)
+	return out
+}
+
+// handleWebhookDeliveries handles GET /api/webhooks/deliveries.
+func (s *KDCAPIServer) handleWebhookDeliveries(rw http.ResponseWriter, req *http.Request) {
+	js, err := json.Marshal(s.dispatcher.Deliveries())
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(js)
+}
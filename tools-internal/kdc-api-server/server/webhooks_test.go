@@ -0,0 +1,122 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignBodyMatchesHMACSHA256(t *testing.T) {
+	secret := "shh"
+	body := []byte(`{"type":"principal.added"}`)
+
+	got := signBody(secret, body)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+
+	if got != want {
+		t.Fatalf("signBody(%q, %q) = %q, want %q", secret, body, got, want)
+	}
+}
+
+func TestDeliverSignsWithTheSinkSecret(t *testing.T) {
+	var gotSig, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		gotBody = string(body)
+		gotSig = req.Header.Get("X-KDC-Signature")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher([]webhookSink{{URL: srv.URL, Secret: "sink-secret"}}, 10)
+	d.Emit(Event{Type: EventPrincipalAdded, Timestamp: time.Now()})
+
+	waitFor(t, func() bool { return gotSig != "" })
+
+	mac := hmac.New(sha256.New, []byte("sink-secret"))
+	mac.Write([]byte(gotBody))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if gotSig != want {
+		t.Fatalf("X-KDC-Signature = %q, want %q", gotSig, want)
+	}
+}
+
+func TestEmitDropsOldestJobOnQueueOverflow(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var delivered []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var ev Event
+		json.NewDecoder(req.Body).Decode(&ev)
+
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+
+		mu.Lock()
+		delivered = append(delivered, ev.Secret)
+		mu.Unlock()
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher([]webhookSink{{URL: srv.URL, Secret: "s"}}, 1)
+
+	// The first Emit's job is picked up by drain() and blocks in the
+	// handler above, waiting on release. The queue (size 1) is now empty
+	// again, so the next two Emits first fill it, then the third Emit
+	// must drop the oldest queued job ("evicted") rather than the newest
+	// ("survivor").
+	d.Emit(Event{Type: EventPrincipalAdded, Secret: "in-flight"})
+	<-started
+
+	d.Emit(Event{Type: EventPrincipalAdded, Secret: "evicted"})
+	d.Emit(Event{Type: EventPrincipalAdded, Secret: "survivor"})
+
+	close(release)
+
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(delivered) >= 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, secret := range delivered {
+		if secret == "evicted" {
+			t.Fatalf("expected the oldest queued job to be dropped on overflow, but it was delivered: %v", delivered)
+		}
+	}
+	if len(delivered) != 2 || delivered[0] != "in-flight" || delivered[1] != "survivor" {
+		t.Fatalf("expected [in-flight survivor] to be delivered, got %v", delivered)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within timeout")
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	"gopkg.in/jcmturner/gokrb5.v7/keytab"
+)
+
+/**
+ * Sortable list of principals
+ */
+type principalList []KPrincipal
+
+func (p principalList) Len() int           { return len(p) }
+func (p principalList) Less(i, j int) bool { return p[i].Full() < p[j].Full() }
+func (p principalList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+type ktKey struct {
+	Type         int32
+	HashContents string
+}
+
+/**
+ * Sortable list of encryption keys
+ */
+type ktKeyList []ktKey
+
+func (p ktKeyList) Len() int           { return len(p) }
+func (p ktKeyList) Less(i, j int) bool { return p[i].Type < p[j].Type }
+func (p ktKeyList) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+// GetPrincipalsChecksum is the exported form of getPrincipalsChecksum, for
+// callers outside this package (e.g. the keytab rotator) that need to
+// compute the same checksum KDCAPIServer/KDCGRPCServer report.
+func GetPrincipalsChecksum(keytabBytes []byte, principals []KPrincipal) (string, error) {
+	return getPrincipalsChecksum(keytabBytes, principals)
+}
+
+/**
+ * getPrincipalsChecksum calculates a checksum with the contents of the keys
+ * of all the principals given, as found in the keytab given. This is the
+ * same logic KDCAPIServer used to run inline - it now lives here so that
+ * every transport computes checksums identically.
+ */
+func getPrincipalsChecksum(keytabBytes []byte, principals []KPrincipal) (string, error) {
+	var kt keytab.Keytab
+	var principalKeys map[string]ktKeyList = make(map[string]ktKeyList)
+	var sortedPrincipals principalList = nil
+	var csumContents string = ""
+
+	err := kt.Unmarshal(keytabBytes)
+	if err != nil {
+		return "", fmt.Errorf(`Unable to parse keytab contents: %s`, err.Error())
+	}
+	for _, entry := range kt.Entries {
+		var p KPrincipal
+		p.Realm = entry.Principal.Realm
+		if len(entry.Principal.Components) > 0 {
+			p.Primary = entry.Principal.Components[0]
+		}
+		if len(entry.Principal.Components) > 1 {
+			p.Instance = entry.Principal.Components[1]
+		}
+
+		var k ktKey
+		k.Type = entry.Key.KeyType
+		k.HashContents = fmt.Sprintf("%d:%x", entry.Key.KeyType, entry.Key.KeyValue)
+
+		var list ktKeyList = nil
+		if l, ok := principalKeys[p.Full()]; ok {
+			list = l
+		}
+
+		list = append(list, k)
+		sort.Sort(list)
+		principalKeys[p.Full()] = list
+	}
+
+	for _, principal := range principals {
+		sortedPrincipals = append(sortedPrincipals, principal)
+	}
+	sort.Sort(sortedPrincipals)
+	for _, principal := range sortedPrincipals {
+		keyList, ok := principalKeys[principal.Full()]
+		if !ok {
+			return "", fmt.Errorf("Could not located principal '%s' in the parsed keytab", principal.Full())
+		}
+
+		for _, key := range keyList {
+			if csumContents != "" {
+				csumContents += ","
+			}
+			csumContents += key.HashContents
+		}
+	}
+
+	sum := sha256.Sum256([]byte(csumContents))
+	return fmt.Sprintf("%x", sum), nil
+}
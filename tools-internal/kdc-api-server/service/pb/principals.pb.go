@@ -0,0 +1,127 @@
+// pb holds stub message/service types for principals.proto, hand-maintained
+// until a real protoc/protoc-gen-go toolchain is wired into the build - do
+// not trust these to round-trip real protobuf wire bytes.
+package pb
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// protoimplTextString gives every message type here a debug-friendly
+// String(). It must walk m's fields with reflection rather than handing m
+// itself to fmt's "%v"/"%+v" - fmt detects that m implements Stringer and
+// calls m.String() right back, which is this function, forever.
+func protoimplTextString(m interface{}) string {
+	v := reflect.ValueOf(m)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i < t.NumField(); i++ {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%s:%v", t.Field(i).Name, v.Field(i).Interface())
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// Principal mirrors the KPrincipal wire type shared with the HTTP API.
+type Principal struct {
+	Realm    string `protobuf:"bytes,1,opt,name=realm,proto3" json:"realm,omitempty"`
+	Primary  string `protobuf:"bytes,2,opt,name=primary,proto3" json:"primary,omitempty"`
+	Instance string `protobuf:"bytes,3,opt,name=instance,proto3" json:"instance,omitempty"`
+}
+
+func (m *Principal) Reset()         { *m = Principal{} }
+func (m *Principal) String() string { return protoimplTextString(m) }
+func (*Principal) ProtoMessage()    {}
+
+// CheckStatus mirrors KDCCheckStatus.
+type CheckStatus struct {
+	Pass     bool   `protobuf:"varint,1,opt,name=pass,proto3" json:"pass,omitempty"`
+	Reason   string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Checksum string `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (m *CheckStatus) Reset()         { *m = CheckStatus{} }
+func (m *CheckStatus) String() string { return protoimplTextString(m) }
+func (*CheckStatus) ProtoMessage()    {}
+
+type AddPrincipalsRequest struct {
+	Principals []*Principal `protobuf:"bytes,1,rep,name=principals,proto3" json:"principals,omitempty"`
+	Secret     string       `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	Binary     bool         `protobuf:"varint,3,opt,name=binary,proto3" json:"binary,omitempty"`
+}
+
+func (m *AddPrincipalsRequest) Reset()         { *m = AddPrincipalsRequest{} }
+func (m *AddPrincipalsRequest) String() string { return protoimplTextString(m) }
+func (*AddPrincipalsRequest) ProtoMessage()    {}
+
+type AddPrincipalsReply struct{}
+
+func (m *AddPrincipalsReply) Reset()         { *m = AddPrincipalsReply{} }
+func (m *AddPrincipalsReply) String() string { return protoimplTextString(m) }
+func (*AddPrincipalsReply) ProtoMessage()    {}
+
+type ListPrincipalsRequest struct {
+	Filter string `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	Secret string `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	Binary bool   `protobuf:"varint,3,opt,name=binary,proto3" json:"binary,omitempty"`
+}
+
+func (m *ListPrincipalsRequest) Reset()         { *m = ListPrincipalsRequest{} }
+func (m *ListPrincipalsRequest) String() string { return protoimplTextString(m) }
+func (*ListPrincipalsRequest) ProtoMessage()    {}
+
+type ListPrincipalsReply struct {
+	List     []*Principal `protobuf:"bytes,1,rep,name=list,proto3" json:"list,omitempty"`
+	Checksum string       `protobuf:"bytes,2,opt,name=checksum,proto3" json:"checksum,omitempty"`
+}
+
+func (m *ListPrincipalsReply) Reset()         { *m = ListPrincipalsReply{} }
+func (m *ListPrincipalsReply) String() string { return protoimplTextString(m) }
+func (*ListPrincipalsReply) ProtoMessage()    {}
+
+type DeletePrincipalsRequest struct {
+	Principals []*Principal `protobuf:"bytes,1,rep,name=principals,proto3" json:"principals,omitempty"`
+	Secret     string       `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	Binary     bool         `protobuf:"varint,3,opt,name=binary,proto3" json:"binary,omitempty"`
+}
+
+func (m *DeletePrincipalsRequest) Reset()         { *m = DeletePrincipalsRequest{} }
+func (m *DeletePrincipalsRequest) String() string { return protoimplTextString(m) }
+func (*DeletePrincipalsRequest) ProtoMessage()    {}
+
+type DeletePrincipalsReply struct{}
+
+func (m *DeletePrincipalsReply) Reset()         { *m = DeletePrincipalsReply{} }
+func (m *DeletePrincipalsReply) String() string { return protoimplTextString(m) }
+func (*DeletePrincipalsReply) ProtoMessage()    {}
+
+type CheckPrincipalsRequest struct {
+	Principals []*Principal `protobuf:"bytes,1,rep,name=principals,proto3" json:"principals,omitempty"`
+	Secret     string       `protobuf:"bytes,2,opt,name=secret,proto3" json:"secret,omitempty"`
+	Binary     bool         `protobuf:"varint,3,opt,name=binary,proto3" json:"binary,omitempty"`
+}
+
+func (m *CheckPrincipalsRequest) Reset()         { *m = CheckPrincipalsRequest{} }
+func (m *CheckPrincipalsRequest) String() string { return protoimplTextString(m) }
+func (*CheckPrincipalsRequest) ProtoMessage()    {}
+
+type CheckPrincipalsReply struct {
+	Check *CheckStatus `protobuf:"bytes,1,opt,name=check,proto3" json:"check,omitempty"`
+}
+
+func (m *CheckPrincipalsReply) Reset()         { *m = CheckPrincipalsReply{} }
+func (m *CheckPrincipalsReply) String() string { return protoimplTextString(m) }
+func (*CheckPrincipalsReply) ProtoMessage()    {}
@@ -0,0 +1,169 @@
+// Hand-maintained gRPC service stubs for principals.proto (KDCClient/
+// KDCServer and the registration plumbing protoc-gen-go-grpc would
+// otherwise emit) - see the note atop principals.pb.go.
+package pb
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+)
+
+// KDCClient is the client API for KDC service.
+type KDCClient interface {
+	AddPrincipals(ctx context.Context, in *AddPrincipalsRequest, opts ...grpc.CallOption) (*AddPrincipalsReply, error)
+	ListPrincipals(ctx context.Context, in *ListPrincipalsRequest, opts ...grpc.CallOption) (*ListPrincipalsReply, error)
+	DeletePrincipals(ctx context.Context, in *DeletePrincipalsRequest, opts ...grpc.CallOption) (*DeletePrincipalsReply, error)
+	CheckPrincipals(ctx context.Context, in *CheckPrincipalsRequest, opts ...grpc.CallOption) (*CheckPrincipalsReply, error)
+}
+
+type kDCClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewKDCClient(cc grpc.ClientConnInterface) KDCClient {
+	return &kDCClient{cc}
+}
+
+func (c *kDCClient) AddPrincipals(ctx context.Context, in *AddPrincipalsRequest, opts ...grpc.CallOption) (*AddPrincipalsReply, error) {
+	out := new(AddPrincipalsReply)
+	if err := c.cc.Invoke(ctx, "/kdc.KDC/AddPrincipals", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kDCClient) ListPrincipals(ctx context.Context, in *ListPrincipalsRequest, opts ...grpc.CallOption) (*ListPrincipalsReply, error) {
+	out := new(ListPrincipalsReply)
+	if err := c.cc.Invoke(ctx, "/kdc.KDC/ListPrincipals", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kDCClient) DeletePrincipals(ctx context.Context, in *DeletePrincipalsRequest, opts ...grpc.CallOption) (*DeletePrincipalsReply, error) {
+	out := new(DeletePrincipalsReply)
+	if err := c.cc.Invoke(ctx, "/kdc.KDC/DeletePrincipals", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *kDCClient) CheckPrincipals(ctx context.Context, in *CheckPrincipalsRequest, opts ...grpc.CallOption) (*CheckPrincipalsReply, error) {
+	out := new(CheckPrincipalsReply)
+	if err := c.cc.Invoke(ctx, "/kdc.KDC/CheckPrincipals", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// KDCServer is the server API for KDC service.
+type KDCServer interface {
+	AddPrincipals(context.Context, *AddPrincipalsRequest) (*AddPrincipalsReply, error)
+	ListPrincipals(context.Context, *ListPrincipalsRequest) (*ListPrincipalsReply, error)
+	DeletePrincipals(context.Context, *DeletePrincipalsRequest) (*DeletePrincipalsReply, error)
+	CheckPrincipals(context.Context, *CheckPrincipalsRequest) (*CheckPrincipalsReply, error)
+	mustEmbedUnimplementedKDCServer()
+}
+
+// UnimplementedKDCServer must be embedded by every KDCServer implementation
+// to keep it forward compatible with service methods added in later
+// revisions of principals.proto.
+type UnimplementedKDCServer struct{}
+
+func (UnimplementedKDCServer) AddPrincipals(context.Context, *AddPrincipalsRequest) (*AddPrincipalsReply, error) {
+	return nil, grpcUnimplemented("AddPrincipals")
+}
+func (UnimplementedKDCServer) ListPrincipals(context.Context, *ListPrincipalsRequest) (*ListPrincipalsReply, error) {
+	return nil, grpcUnimplemented("ListPrincipals")
+}
+func (UnimplementedKDCServer) DeletePrincipals(context.Context, *DeletePrincipalsRequest) (*DeletePrincipalsReply, error) {
+	return nil, grpcUnimplemented("DeletePrincipals")
+}
+func (UnimplementedKDCServer) CheckPrincipals(context.Context, *CheckPrincipalsRequest) (*CheckPrincipalsReply, error) {
+	return nil, grpcUnimplemented("CheckPrincipals")
+}
+func (UnimplementedKDCServer) mustEmbedUnimplementedKDCServer() {}
+
+func grpcUnimplemented(method string) error {
+	return fmt.Errorf("method %s not implemented", method)
+}
+
+// RegisterKDCServer registers srv with s under the KDC service name.
+func RegisterKDCServer(s *grpc.Server, srv KDCServer) {
+	s.RegisterService(&kDCServiceDesc, srv)
+}
+
+var kDCServiceDesc = grpc.ServiceDesc{
+	ServiceName: "kdc.KDC",
+	HandlerType: (*KDCServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "AddPrincipals", Handler: kDCAddPrincipalsHandler},
+		{MethodName: "ListPrincipals", Handler: kDCListPrincipalsHandler},
+		{MethodName: "DeletePrincipals", Handler: kDCDeletePrincipalsHandler},
+		{MethodName: "CheckPrincipals", Handler: kDCCheckPrincipalsHandler},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "principals.proto",
+}
+
+func kDCAddPrincipalsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddPrincipalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KDCServer).AddPrincipals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kdc.KDC/AddPrincipals"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KDCServer).AddPrincipals(ctx, req.(*AddPrincipalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func kDCListPrincipalsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPrincipalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KDCServer).ListPrincipals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kdc.KDC/ListPrincipals"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KDCServer).ListPrincipals(ctx, req.(*ListPrincipalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func kDCDeletePrincipalsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePrincipalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KDCServer).DeletePrincipals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kdc.KDC/DeletePrincipals"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KDCServer).DeletePrincipals(ctx, req.(*DeletePrincipalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func kDCCheckPrincipalsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckPrincipalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(KDCServer).CheckPrincipals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/kdc.KDC/CheckPrincipals"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(KDCServer).CheckPrincipals(ctx, req.(*CheckPrincipalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
@@ -0,0 +1,33 @@
+package pb
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMessageStringDoesNotRecurse guards against protoimplTextString handing
+// a message straight to fmt, which would detect the message's own Stringer
+// and call String() right back into protoimplTextString forever.
+func TestMessageStringDoesNotRecurse(t *testing.T) {
+	messages := []fmt.Stringer{
+		&Principal{Realm: "EXAMPLE.COM", Primary: "alice", Instance: "admin"},
+		&CheckStatus{Pass: true, Reason: "ok", Checksum: "abc123"},
+		&AddPrincipalsRequest{Principals: []*Principal{{Realm: "EXAMPLE.COM", Primary: "alice"}}, Secret: "kdc/secret"},
+		&AddPrincipalsReply{},
+		&ListPrincipalsRequest{Filter: "alice*"},
+		&ListPrincipalsReply{List: []*Principal{{Realm: "EXAMPLE.COM", Primary: "alice"}}, Checksum: "abc123"},
+		&DeletePrincipalsRequest{Secret: "kdc/secret"},
+		&DeletePrincipalsReply{},
+		&CheckPrincipalsRequest{Secret: "kdc/secret"},
+		&CheckPrincipalsReply{Check: &CheckStatus{Pass: true}},
+	}
+
+	for _, m := range messages {
+		if got := m.String(); got == "" {
+			t.Errorf("%T.String() returned an empty string", m)
+		}
+		if got := fmt.Sprintf("%v", m); got == "" {
+			t.Errorf("fmt.Sprintf(%%v, %T) returned an empty string", m)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"fmt"
+)
+
+/**
+ * Service implements the principal lifecycle operations that used to live
+ * directly inside KDCAPIServer's http.HandlerFuncs. It knows nothing about
+ * HTTP, gRPC or any other transport - it only deals with typed requests and
+ * responses, so that KDCAPIServer and KDCGRPCServer can both sit on top of
+ * it without duplicating validation, DC/OS reconnect or checksum logic.
+ */
+type Service struct {
+	kadmin   KAdmin
+	dcosDial func() (DCOSClient, error)
+}
+
+/**
+ * KAdmin is the subset of the kdc-api-server's KAdminClient that the
+ * service layer depends on. It is expressed as an interface here so that
+ * the service package does not depend on that concrete client, and so that
+ * it can be faked out in tests.
+ */
+type KAdmin interface {
+	AddMissingPrincipals(principals []KPrincipal) error
+	GetKeytabForPrincipals(principals []KPrincipal) ([]byte, error)
+	DeletePrincipals(principals []KPrincipal) error
+	ListPrincipals(filter string) ([]KPrincipal, error)
+	HasPrincipal(principal KPrincipal) (bool, error)
+	HasPrincipalInKeytab(keytabBytes []byte, principal *KPrincipal) (bool, error)
+}
+
+/**
+ * DCOSClient is the subset of the DC/OS client that the service layer needs
+ * in order to read and write keytab secrets.
+ */
+type DCOSClient interface {
+	CreateKeytabSecret(name string, keytab []byte, binary bool) error
+	GetKeytabSecret(name string, binary bool) ([]byte, error)
+	DeleteKeytabSecret(name string, binary bool) error
+}
+
+/**
+ * KPrincipal mirrors the wire-level representation of a kerberos principal.
+ * It is intentionally identical in shape to the one previously declared
+ * next to KDCAPIServer, since it is part of the public request/response
+ * contract shared by every transport.
+ */
+type KPrincipal struct {
+	Realm    string `json:"realm"`
+	Primary  string `json:"primary"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func (p KPrincipal) Full() string {
+	if p.Instance != "" {
+		return fmt.Sprintf("%s/%s@%s", p.Primary, p.Instance, p.Realm)
+	}
+	return fmt.Sprintf("%s@%s", p.Primary, p.Realm)
+}
+
+func (p KPrincipal) String() string {
+	return p.Full()
+}
+
+// NewService wires a KAdmin implementation and a DC/OS client factory into
+// a Service. The dcosDial factory is invoked per-request (mirroring the
+// previous re-connect-on-every-request behaviour) since the downstream
+// DC/OS token can expire at any time.
+func NewService(kadmin KAdmin, dcosDial func() (DCOSClient, error)) *Service {
+	return &Service{
+		kadmin:   kadmin,
+		dcosDial: dcosDial,
+	}
+}
+
+type AddPrincipalsRequest struct {
+	Principals []KPrincipal
+	Secret     string
+	Binary     bool
+}
+
+type AddPrincipalsResponse struct{}
+
+// AddPrincipals ports the body of the old handleAddPrincipal: it adds any
+// missing principals to KDC, exports a keytab for the full set, and
+// uploads it to the requested secret.
+func (s *Service) AddPrincipals(req AddPrincipalsRequest) (*AddPrincipalsResponse, error) {
+	if len(req.Principals) == 0 {
+		return nil, fmt.Errorf("given an empty list of principals")
+	}
+
+	dclient, err := s.dcosDial()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	if err := s.kadmin.AddMissingPrincipals(req.Principals); err != nil {
+		return nil, fmt.Errorf("Unable to add principals: %s", err.Error())
+	}
+
+	keytab, err := s.kadmin.GetKeytabForPrincipals(req.Principals)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to export keytab: %s", err.Error())
+	}
+
+	if err := dclient.CreateKeytabSecret(req.Secret, keytab, req.Binary); err != nil {
+		return nil, fmt.Errorf("Unable to upload to secret store: %s", err.Error())
+	}
+
+	return &AddPrincipalsResponse{}, nil
+}
+
+type ListPrincipalsRequest struct {
+	Filter string
+	Secret string
+	Binary bool
+}
+
+type ListPrincipalsResponse struct {
+	List     []KPrincipal
+	Checksum string
+}
+
+// ListPrincipals ports the body of the old handleListPrincipals.
+func (s *Service) ListPrincipals(req ListPrincipalsRequest) (*ListPrincipalsResponse, error) {
+	list, err := s.kadmin.ListPrincipals(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list principals: %s", err.Error())
+	}
+
+	resp := &ListPrincipalsResponse{List: list}
+	if req.Secret == "" {
+		return resp, nil
+	}
+
+	dclient, err := s.dcosDial()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	ktBytes, err := dclient.GetKeytabSecret(req.Secret, req.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the keytab secret: %s", err.Error())
+	}
+	if ktBytes == nil {
+		return nil, fmt.Errorf("The secret was empty")
+	}
+
+	var filtered []KPrincipal
+	for _, principal := range list {
+		ok, err := s.kadmin.HasPrincipalInKeytab(ktBytes, &principal)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to check if principal %s exists in keytab: %s", principal.Full(), err.Error())
+		}
+		if ok {
+			filtered = append(filtered, principal)
+		}
+	}
+	resp.List = filtered
+
+	csum, err := getPrincipalsChecksum(ktBytes, filtered)
+	if err != nil {
+		return nil, err
+	}
+	resp.Checksum = csum
+
+	return resp, nil
+}
+
+type DeletePrincipalsRequest struct {
+	Principals []KPrincipal
+	Secret     string
+	Binary     bool
+}
+
+type DeletePrincipalsResponse struct{}
+
+// DeletePrincipals ports the body of the old handleDeletePrincipals.
+func (s *Service) DeletePrincipals(req DeletePrincipalsRequest) (*DeletePrincipalsResponse, error) {
+	if len(req.Principals) == 0 {
+		return nil, fmt.Errorf("given an empty list of principals")
+	}
+
+	dclient, err := s.dcosDial()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	if err := dclient.DeleteKeytabSecret(req.Secret, req.Binary); err != nil {
+		return nil, fmt.Errorf("Unable to delete secret: %s", err.Error())
+	}
+
+	if err := s.kadmin.DeletePrincipals(req.Principals); err != nil {
+		return nil, fmt.Errorf("Unable to delete principals: %s", err.Error())
+	}
+
+	return &DeletePrincipalsResponse{}, nil
+}
+
+type CheckPrincipalsRequest struct {
+	Principals []KPrincipal
+	Secret     string
+	Binary     bool
+}
+
+type CheckPrincipalsResponse struct {
+	Pass     bool
+	Reason   string
+	Checksum string
+}
+
+// CheckPrincipals ports the body of the old handleCheckPrincipals.
+func (s *Service) CheckPrincipals(req CheckPrincipalsRequest) (*CheckPrincipalsResponse, error) {
+	if len(req.Principals) == 0 {
+		return nil, fmt.Errorf("given an empty list of principals")
+	}
+
+	for _, principal := range req.Principals {
+		ok, err := s.kadmin.HasPrincipal(principal)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to check if principal %s exists: %s", principal.Full(), err.Error())
+		}
+		if !ok {
+			return &CheckPrincipalsResponse{
+				Pass:   false,
+				Reason: fmt.Sprintf("Principal '%s' does not exist in kerberos", principal.Full()),
+			}, nil
+		}
+	}
+
+	dclient, err := s.dcosDial()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to DC/OS: %s", err.Error())
+	}
+
+	ktBytes, err := dclient.GetKeytabSecret(req.Secret, req.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the keytab secret: %s", err.Error())
+	}
+	if ktBytes == nil {
+		return &CheckPrincipalsResponse{
+			Pass:   false,
+			Reason: fmt.Sprintf("Secret '%s' does not exist", req.Secret),
+		}, nil
+	}
+
+	for _, principal := range req.Principals {
+		ok, err := s.kadmin.HasPrincipalInKeytab(ktBytes, &principal)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to check if principal %s exists in keytab: %s", principal.Full(), err.Error())
+		}
+		if !ok {
+			return &CheckPrincipalsResponse{
+				Pass:   false,
+				Reason: fmt.Sprintf("Principal '%s' does not exist in keytab", principal.Full()),
+			}, nil
+		}
+	}
+
+	csum, err := getPrincipalsChecksum(ktBytes, req.Principals)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CheckPrincipalsResponse{Pass: true, Checksum: csum}, nil
+}